@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"log"
+	"os"
+	"time"
+
+	rediscache "github.com/go-redis/cache/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// DemoCache wraps an optional Redis-backed cache of parsed demo results,
+// keyed by the SHA-1 of the demo bytes so repeat submissions of the same
+// demo skip the parser entirely.
+type DemoCache struct {
+	cache *rediscache.Cache
+	ttl   time.Duration
+}
+
+// newDemoCache builds a DemoCache from REDIS_URL. It returns nil (not an
+// error) when REDIS_URL is unset, or if the URL can't be parsed, so the
+// service degrades gracefully to today's stateless behavior.
+func newDemoCache() *DemoCache {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		return nil
+	}
+
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		log.Printf("Invalid REDIS_URL, running without a result cache: %v", err)
+		return nil
+	}
+
+	client := redis.NewClient(opt)
+
+	return &DemoCache{
+		cache: rediscache.New(&rediscache.Options{
+			Redis:     client,
+			Marshal:   marshalGzipGob,
+			Unmarshal: unmarshalGzipGob,
+		}),
+		ttl: demoCacheTTL(),
+	}
+}
+
+func demoCacheTTL() time.Duration {
+	if raw := os.Getenv("REDIS_CACHE_TTL"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+	}
+	return 24 * time.Hour
+}
+
+// demoCacheKey is the SHA-1 hex digest of the raw demo bytes.
+func demoCacheKey(demoData []byte) string {
+	sum := sha1.Sum(demoData)
+	return "demo:" + hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached ParsedData for these demo bytes, if any. It's a
+// no-op miss when c is nil so callers don't need to guard every call site.
+func (c *DemoCache) Get(ctx context.Context, demoData []byte) (*ParsedData, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	var parsed ParsedData
+	if err := c.cache.Get(ctx, demoCacheKey(demoData), &parsed); err != nil {
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// Set stores the parsed result under the demo's content hash. Failures
+// are logged, not propagated — the cache is an optimization, not a
+// dependency the parse should fail on.
+func (c *DemoCache) Set(ctx context.Context, demoData []byte, parsed *ParsedData) {
+	if c == nil {
+		return
+	}
+
+	err := c.cache.Set(&rediscache.Item{
+		Ctx:   ctx,
+		Key:   demoCacheKey(demoData),
+		Value: parsed,
+		TTL:   c.ttl,
+	})
+	if err != nil {
+		log.Printf("Error caching parsed demo: %v", err)
+	}
+}
+
+// marshalGzipGob and unmarshalGzipGob back the Redis cache's encoding.
+// The events array dominates a ParsedData's size, so gob (denser than
+// JSON for repeated struct shapes) plus gzip keeps Redis memory bounded.
+func marshalGzipGob(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	if err := gob.NewEncoder(gz).Encode(v); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func unmarshalGzipGob(data []byte, v interface{}) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	return gob.NewDecoder(gz).Decode(v)
+}
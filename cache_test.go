@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestDemoCacheKey(t *testing.T) {
+	a := demoCacheKey([]byte("demo bytes"))
+	b := demoCacheKey([]byte("demo bytes"))
+	if a != b {
+		t.Errorf("demoCacheKey is not deterministic: %q != %q", a, b)
+	}
+
+	const prefix = "demo:"
+	if len(a) != len(prefix)+40 {
+		t.Errorf("demoCacheKey length = %d, want %d (prefix + SHA-1 hex)", len(a), len(prefix)+40)
+	}
+	if a[:len(prefix)] != prefix {
+		t.Errorf("demoCacheKey = %q, want it prefixed with %q", a, prefix)
+	}
+
+	if other := demoCacheKey([]byte("different bytes")); other == a {
+		t.Errorf("demoCacheKey collided for different inputs: %q", a)
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+
+	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+)
+
+// BuyType classifies a team's spending for a round.
+type BuyType string
+
+const (
+	BuyFull  BuyType = "full_buy"
+	BuyForce BuyType = "force_buy"
+	BuyHalf  BuyType = "half_buy"
+	BuyEco   BuyType = "eco"
+)
+
+// RoundEconomy is the equipment and bomb-timing summary for one round.
+type RoundEconomy struct {
+	TEquipValue    int                       `json:"tEquipValue"`
+	CTEquipValue   int                       `json:"ctEquipValue"`
+	TMoneySpent    int                       `json:"tMoneySpent"`
+	CTMoneySpent   int                       `json:"ctMoneySpent"`
+	TBuyType       BuyType                   `json:"tBuyType"`
+	CTBuyType      BuyType                   `json:"ctBuyType"`
+	BombPlantTick  int                       `json:"bombPlantTick,omitempty"`
+	BombPlantSite  string                    `json:"bombPlantSite,omitempty"`
+	BombDefuseTick int                       `json:"bombDefuseTick,omitempty"`
+	DamageMatrix   map[string]map[string]int `json:"damageMatrix"`
+}
+
+// PlayerRoundStats is one player's utility/trade/clutch line for a single
+// round, keyed by SteamID in Round.PlayerStats.
+type PlayerRoundStats struct {
+	HPDealt                int     `json:"hpDealt"`
+	HPTaken                int     `json:"hpTaken"`
+	UtilityDamage          int     `json:"utilityDamage"`
+	FlashesThrown          int     `json:"flashesThrown"`
+	EnemiesFlashed         int     `json:"enemiesFlashed"`
+	FlashDurationOnEnemies float64 `json:"flashDurationOnEnemies"`
+	TradeKills             int     `json:"tradeKills"`
+	TradedDeaths           int     `json:"tradedDeaths"`
+	OpeningKill            bool    `json:"openingKill"`
+	OpeningDeath           bool    `json:"openingDeath"`
+	ClutchAttempted        bool    `json:"clutchAttempted"`
+	ClutchWon              bool    `json:"clutchWon"`
+}
+
+// classifyBuy buckets a team's round into a buy type from its freeze-time
+// equipment value and how much it spent to get there. A team that spent
+// heavily but still landed below the full-buy threshold is a force-buy;
+// one that mostly carried gear from previous rounds is a half-buy.
+func classifyBuy(equipValue, moneySpent, playerCount int) BuyType {
+	if playerCount == 0 {
+		return BuyEco
+	}
+
+	avgEquip := equipValue / playerCount
+	avgSpent := moneySpent / playerCount
+
+	switch {
+	case avgEquip >= 4000:
+		return BuyFull
+	case avgEquip < 1500:
+		return BuyEco
+	case avgSpent >= 3000:
+		return BuyForce
+	default:
+		return BuyHalf
+	}
+}
+
+// economyEngine registers the handlers that back Round.Economy and
+// Round.PlayerStats: equipment/buy classification, the per-round damage
+// matrix, and per-player utility/trade/clutch tracking. Unlike statsEngine
+// it's always attached, since this is data the base response already
+// claims to have (on Round/Player) rather than an opt-in extra.
+type economyEngine struct {
+	p           demoinfocs.Parser
+	playerStats map[uint64]*Player
+
+	roundHPDealt        map[uint64]int
+	roundHPTaken        map[uint64]int
+	roundUtilityDamage  map[uint64]int
+	roundFlashesThrown  map[uint64]int
+	roundEnemiesFlashed map[uint64]int
+	roundFlashDuration  map[uint64]float64
+	roundTradeKills     map[uint64]int
+	roundTradedDeaths   map[uint64]int
+	roundOpeningKill    map[uint64]bool
+	roundOpeningDeath   map[uint64]bool
+	roundClutchAttempt  map[uint64]bool
+	roundClutchTeam     map[uint64]common.Team
+	roundDamage         map[uint64]map[uint64]int
+	roundDeaths         []deathRecord
+	roundHadKill        bool
+	roundLastFlashedBy  map[uint64]uint64
+
+	bombPlantTick  int
+	bombPlantSite  string
+	bombDefuseTick int
+
+	// Match-cumulative totals behind Player.Damage/HeadshotKills/etc, kept
+	// here (rather than statsEngine) since economyEngine is always attached
+	// and these are cheap running counters, unlike ADR/KAST/Rating's
+	// per-round bookkeeping.
+	totalDamage         map[uint64]int
+	totalHeadshotKills  map[uint64]int
+	totalFlashAssists   map[uint64]int
+	totalMVPs           map[uint64]int
+	totalClutchWins     map[uint64]int
+	totalFirstKills     map[uint64]int
+	totalFirstDeaths    map[uint64]int
+	totalUtilityDamage  map[uint64]int
+	totalEnemiesFlashed map[uint64]int
+}
+
+func newEconomyEngine(p demoinfocs.Parser, playerStats map[uint64]*Player) *economyEngine {
+	return &economyEngine{
+		p:                   p,
+		playerStats:         playerStats,
+		totalDamage:         make(map[uint64]int),
+		totalHeadshotKills:  make(map[uint64]int),
+		totalFlashAssists:   make(map[uint64]int),
+		totalMVPs:           make(map[uint64]int),
+		totalClutchWins:     make(map[uint64]int),
+		totalFirstKills:     make(map[uint64]int),
+		totalFirstDeaths:    make(map[uint64]int),
+		totalUtilityDamage:  make(map[uint64]int),
+		totalEnemiesFlashed: make(map[uint64]int),
+	}
+}
+
+func (e *economyEngine) attach() {
+	e.p.RegisterEventHandler(e.onRoundStart)
+	e.p.RegisterEventHandler(e.onPlayerHurt)
+	e.p.RegisterEventHandler(e.onWeaponFire)
+	e.p.RegisterEventHandler(e.onPlayerFlashed)
+	e.p.RegisterEventHandler(e.onGrenadeProjectileThrow)
+	e.p.RegisterEventHandler(e.onItemPickup)
+	e.p.RegisterEventHandler(e.onKill)
+	e.p.RegisterEventHandler(e.onBombPlanted)
+	e.p.RegisterEventHandler(e.onBombDefused)
+	e.p.RegisterEventHandler(e.onRoundMVPAnnouncement)
+}
+
+func (e *economyEngine) onRoundStart(ev events.RoundStart) {
+	e.roundHPDealt = make(map[uint64]int)
+	e.roundHPTaken = make(map[uint64]int)
+	e.roundUtilityDamage = make(map[uint64]int)
+	e.roundFlashesThrown = make(map[uint64]int)
+	e.roundEnemiesFlashed = make(map[uint64]int)
+	e.roundFlashDuration = make(map[uint64]float64)
+	e.roundTradeKills = make(map[uint64]int)
+	e.roundTradedDeaths = make(map[uint64]int)
+	e.roundOpeningKill = make(map[uint64]bool)
+	e.roundOpeningDeath = make(map[uint64]bool)
+	e.roundClutchAttempt = make(map[uint64]bool)
+	e.roundClutchTeam = make(map[uint64]common.Team)
+	e.roundDamage = make(map[uint64]map[uint64]int)
+	e.roundDeaths = nil
+	e.roundHadKill = false
+	e.roundLastFlashedBy = make(map[uint64]uint64)
+	e.bombPlantTick = 0
+	e.bombPlantSite = ""
+	e.bombDefuseTick = 0
+}
+
+func (e *economyEngine) onPlayerHurt(ev events.PlayerHurt) {
+	if ev.Player != nil {
+		e.roundHPTaken[ev.Player.SteamID64] += ev.HealthDamageTaken
+	}
+
+	if ev.Attacker == nil || ev.Player == nil || ev.Attacker.SteamID64 == ev.Player.SteamID64 || ev.Attacker.Team == ev.Player.Team {
+		return
+	}
+
+	e.roundHPDealt[ev.Attacker.SteamID64] += ev.HealthDamageTaken
+	e.totalDamage[ev.Attacker.SteamID64] += ev.HealthDamageTaken
+
+	if ev.Weapon != nil && ev.Weapon.Class() == common.EqClassGrenade {
+		e.roundUtilityDamage[ev.Attacker.SteamID64] += ev.HealthDamageTaken
+		e.totalUtilityDamage[ev.Attacker.SteamID64] += ev.HealthDamageTaken
+	}
+
+	if e.roundDamage[ev.Attacker.SteamID64] == nil {
+		e.roundDamage[ev.Attacker.SteamID64] = make(map[uint64]int)
+	}
+	e.roundDamage[ev.Attacker.SteamID64][ev.Player.SteamID64] += ev.HealthDamageTaken
+}
+
+func (e *economyEngine) onWeaponFire(ev events.WeaponFire) {
+	player := getOrCreatePlayer(e.playerStats, ev.Shooter)
+	if player == nil {
+		return
+	}
+	incrementStat(player, "shotsFired")
+}
+
+func (e *economyEngine) onPlayerFlashed(ev events.PlayerFlashed) {
+	if ev.Attacker == nil || ev.Player == nil || ev.Attacker.SteamID64 == ev.Player.SteamID64 || ev.Attacker.Team == ev.Player.Team {
+		return
+	}
+
+	getOrCreatePlayer(e.playerStats, ev.Attacker)
+	e.roundEnemiesFlashed[ev.Attacker.SteamID64]++
+	e.totalEnemiesFlashed[ev.Attacker.SteamID64]++
+	e.roundFlashDuration[ev.Attacker.SteamID64] += ev.FlashDuration().Seconds()
+	e.roundLastFlashedBy[ev.Player.SteamID64] = ev.Attacker.SteamID64
+}
+
+func (e *economyEngine) onGrenadeProjectileThrow(ev events.GrenadeProjectileThrow) {
+	thrower := ev.Projectile.Thrower
+	weapon := ev.Projectile.WeaponInstance
+	if thrower == nil || weapon == nil || weapon.Type != common.EqFlash {
+		return
+	}
+
+	getOrCreatePlayer(e.playerStats, thrower)
+	e.roundFlashesThrown[thrower.SteamID64]++
+}
+
+func (e *economyEngine) onItemPickup(ev events.ItemPickup) {
+	player := getOrCreatePlayer(e.playerStats, ev.Player)
+	if player == nil {
+		return
+	}
+	incrementStat(player, "itemsPickedUp")
+}
+
+func (e *economyEngine) onKill(ev events.Kill) {
+	tick := e.p.GameState().IngameTick()
+
+	if !e.roundHadKill {
+		e.roundHadKill = true
+		if ev.Killer != nil {
+			e.roundOpeningKill[ev.Killer.SteamID64] = true
+			e.totalFirstKills[ev.Killer.SteamID64]++
+		}
+		if ev.Victim != nil {
+			e.roundOpeningDeath[ev.Victim.SteamID64] = true
+			e.totalFirstDeaths[ev.Victim.SteamID64]++
+		}
+	}
+
+	if ev.Killer != nil && ev.IsHeadshot {
+		e.totalHeadshotKills[ev.Killer.SteamID64]++
+	}
+
+	if ev.AssistedFlash && ev.Victim != nil {
+		if flasher, ok := e.roundLastFlashedBy[ev.Victim.SteamID64]; ok && flasher != 0 {
+			e.totalFlashAssists[flasher]++
+		}
+	}
+
+	if ev.Killer != nil && ev.Victim != nil {
+		for _, d := range e.roundDeaths {
+			if d.killer == ev.Victim.SteamID64 && d.team == ev.Killer.Team && withinTradeWindow(e.p, d.tick, tick) {
+				e.roundTradeKills[ev.Killer.SteamID64]++
+				e.roundTradedDeaths[d.victim]++
+			}
+		}
+
+		e.roundDeaths = append(e.roundDeaths, deathRecord{
+			victim: ev.Victim.SteamID64,
+			killer: ev.Killer.SteamID64,
+			team:   ev.Victim.Team,
+			tick:   tick,
+		})
+	}
+
+	e.trackClutch()
+}
+
+// trackClutch flags the lone survivor on either team, the first time a
+// round drops to a 1vX, as having attempted a clutch.
+func (e *economyEngine) trackClutch() {
+	gs := e.p.GameState()
+	e.maybeMarkClutch(gs.TeamTerrorists())
+	e.maybeMarkClutch(gs.TeamCounterTerrorists())
+}
+
+func (e *economyEngine) maybeMarkClutch(team *common.TeamState) {
+	var lone *common.Player
+	alive := 0
+	for _, member := range team.Members() {
+		if member.IsAlive() {
+			alive++
+			lone = member
+		}
+	}
+	if alive != 1 || lone == nil || e.roundClutchAttempt[lone.SteamID64] {
+		return
+	}
+
+	opponentAlive := 0
+	for _, member := range team.Opponent.Members() {
+		if member.IsAlive() {
+			opponentAlive++
+		}
+	}
+	if opponentAlive == 0 {
+		return
+	}
+
+	e.roundClutchAttempt[lone.SteamID64] = true
+	e.roundClutchTeam[lone.SteamID64] = team.Team()
+}
+
+func (e *economyEngine) onBombPlanted(ev events.BombPlanted) {
+	e.bombPlantTick = e.p.GameState().IngameTick()
+	e.bombPlantSite = string(ev.Site)
+}
+
+func (e *economyEngine) onBombDefused(ev events.BombDefused) {
+	e.bombDefuseTick = e.p.GameState().IngameTick()
+}
+
+func (e *economyEngine) onRoundMVPAnnouncement(ev events.RoundMVPAnnouncement) {
+	if ev.Player == nil {
+		return
+	}
+	e.totalMVPs[ev.Player.SteamID64]++
+}
+
+// snapshotRound reads the round just played into a RoundEconomy and a
+// per-player stats map, resolving clutch outcomes against winnerSide.
+// Called from the RoundEnd handler that builds the rest of the Round.
+func (e *economyEngine) snapshotRound(winnerSide string) (RoundEconomy, map[string]PlayerRoundStats) {
+	gs := e.p.GameState()
+	t := gs.TeamTerrorists()
+	ct := gs.TeamCounterTerrorists()
+
+	economy := RoundEconomy{
+		TEquipValue:    t.FreezeTimeEndEquipmentValue(),
+		CTEquipValue:   ct.FreezeTimeEndEquipmentValue(),
+		TMoneySpent:    t.MoneySpentThisRound(),
+		CTMoneySpent:   ct.MoneySpentThisRound(),
+		TBuyType:       classifyBuy(t.FreezeTimeEndEquipmentValue(), t.MoneySpentThisRound(), len(t.Members())),
+		CTBuyType:      classifyBuy(ct.FreezeTimeEndEquipmentValue(), ct.MoneySpentThisRound(), len(ct.Members())),
+		BombPlantTick:  e.bombPlantTick,
+		BombPlantSite:  e.bombPlantSite,
+		BombDefuseTick: e.bombDefuseTick,
+		DamageMatrix:   e.buildDamageMatrix(),
+	}
+
+	playerStats := make(map[string]PlayerRoundStats, len(e.playerStats))
+	for steamID, player := range e.playerStats {
+		clutchAttempted := e.roundClutchAttempt[steamID]
+		clutchWon := clutchAttempted && teamString(e.roundClutchTeam[steamID]) == winnerSide
+		if clutchWon {
+			e.totalClutchWins[steamID]++
+		}
+
+		playerStats[player.SteamID] = PlayerRoundStats{
+			HPDealt:                e.roundHPDealt[steamID],
+			HPTaken:                e.roundHPTaken[steamID],
+			UtilityDamage:          e.roundUtilityDamage[steamID],
+			FlashesThrown:          e.roundFlashesThrown[steamID],
+			EnemiesFlashed:         e.roundEnemiesFlashed[steamID],
+			FlashDurationOnEnemies: e.roundFlashDuration[steamID],
+			TradeKills:             e.roundTradeKills[steamID],
+			TradedDeaths:           e.roundTradedDeaths[steamID],
+			OpeningKill:            e.roundOpeningKill[steamID],
+			OpeningDeath:           e.roundOpeningDeath[steamID],
+			ClutchAttempted:        clutchAttempted,
+			ClutchWon:              clutchWon,
+		}
+	}
+
+	return economy, playerStats
+}
+
+// finalize writes the match-cumulative totals onto every tracked player, so
+// buildGet5Teams (and the base /parse response) carry real numbers for
+// damage/headshots/MVPs/clutches/first-kills/first-deaths/utility/flashes
+// instead of zero values, regardless of whether ?stats=full was requested.
+func (e *economyEngine) finalize() {
+	for steamID, player := range e.playerStats {
+		player.Damage = e.totalDamage[steamID]
+		player.HeadshotKills = e.totalHeadshotKills[steamID]
+		player.FlashAssists = e.totalFlashAssists[steamID]
+		player.MVPs = e.totalMVPs[steamID]
+		player.Clutches1vN = e.totalClutchWins[steamID]
+		player.FirstKills = e.totalFirstKills[steamID]
+		player.FirstDeaths = e.totalFirstDeaths[steamID]
+		player.UtilityDamage = e.totalUtilityDamage[steamID]
+		player.EnemiesFlashed = e.totalEnemiesFlashed[steamID]
+	}
+}
+
+func (e *economyEngine) buildDamageMatrix() map[string]map[string]int {
+	matrix := make(map[string]map[string]int, len(e.roundDamage))
+	for attacker, victims := range e.roundDamage {
+		row := make(map[string]int, len(victims))
+		for victim, damage := range victims {
+			row[steamIDKey(victim)] = damage
+		}
+		matrix[steamIDKey(attacker)] = row
+	}
+	return matrix
+}
+
+func steamIDKey(id uint64) string {
+	return fmt.Sprintf("%d", id)
+}
+
+func incrementStat(player *Player, key string) {
+	if player.Stats == nil {
+		player.Stats = make(map[string]interface{})
+	}
+	count, _ := player.Stats[key].(int)
+	player.Stats[key] = count + 1
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestClassifyBuy(t *testing.T) {
+	tests := []struct {
+		name        string
+		equipValue  int
+		moneySpent  int
+		playerCount int
+		want        BuyType
+	}{
+		{"no players", 0, 0, 0, BuyEco},
+		{"full buy", 20000, 20000, 5, BuyFull},
+		{"eco", 2000, 0, 5, BuyEco},
+		{"force buy", 10000, 15000, 5, BuyForce},
+		{"half buy", 10000, 2000, 5, BuyHalf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyBuy(tt.equipValue, tt.moneySpent, tt.playerCount); got != tt.want {
+				t.Errorf("classifyBuy(%d, %d, %d) = %v, want %v", tt.equipValue, tt.moneySpent, tt.playerCount, got, tt.want)
+			}
+		})
+	}
+}
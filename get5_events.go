@@ -0,0 +1,107 @@
+package main
+
+// MatchEvent is the envelope every Get5/MatchZy-style webhook event embeds.
+type MatchEvent struct {
+	Event   string `json:"event"`
+	MatchID int    `json:"matchid"`
+}
+
+// MapEvent extends MatchEvent with the map number for map-scoped events.
+type MapEvent struct {
+	MatchEvent
+	MapNumber int `json:"map_number"`
+}
+
+// Get5StatsPlayer mirrors the per-player stat block Get5 attaches to
+// map_result and series_end events.
+type Get5StatsPlayer struct {
+	SteamID        string  `json:"steamid"`
+	Name           string  `json:"name"`
+	Kills          int     `json:"kills"`
+	Deaths         int     `json:"deaths"`
+	Assists        int     `json:"assists"`
+	Damage         int     `json:"damage"`
+	HeadshotKills  int     `json:"headshot_kills"`
+	FlashAssists   int     `json:"flash_assists"`
+	KAST           float64 `json:"kast"`
+	ADR            float64 `json:"adr"`
+	MVPs           int     `json:"mvps"`
+	Clutches1vN    int     `json:"1vx_wins"`
+	FirstKills     int     `json:"first_kills"`
+	FirstDeaths    int     `json:"first_deaths"`
+	UtilityDamage  int     `json:"utility_damage"`
+	EnemiesFlashed int     `json:"enemies_flashed"`
+}
+
+// Get5StatsTeam mirrors the per-team stat block Get5 attaches to
+// map_result and series_end events.
+type Get5StatsTeam struct {
+	Name    string            `json:"name"`
+	Side    string            `json:"side"`
+	Score   int               `json:"score"`
+	Players []Get5StatsPlayer `json:"players"`
+}
+
+// SeriesInitEvent is fired once, before any map-scoped events, when a
+// series begins parsing.
+type SeriesInitEvent struct {
+	MatchEvent
+	NumMaps int `json:"num_maps"`
+}
+
+// MapResultEvent is fired when a map finishes, carrying the final
+// per-team and per-player stat blocks.
+type MapResultEvent struct {
+	MapEvent
+	WinnerSide string        `json:"winner_side"`
+	Team1      Get5StatsTeam `json:"team1"`
+	Team2      Get5StatsTeam `json:"team2"`
+}
+
+// RoundEndEvent is fired after every round.
+type RoundEndEvent struct {
+	MapEvent
+	RoundNumber int    `json:"round_number"`
+	RoundTime   int    `json:"round_time"`
+	WinnerSide  string `json:"winner_side"`
+	Reason      string `json:"reason"`
+	Team1Score  int    `json:"team1_score"`
+	Team2Score  int    `json:"team2_score"`
+}
+
+// PlayerDeathEvent is fired on every kill.
+type PlayerDeathEvent struct {
+	MapEvent
+	RoundNumber   int    `json:"round_number"`
+	VictimSteamID string `json:"victim_steamid"`
+	AttackerSteam string `json:"attacker_steamid"`
+	AssisterSteam string `json:"assister_steamid"`
+	Weapon        string `json:"weapon"`
+	Headshot      bool   `json:"headshot"`
+	FlashAssisted bool   `json:"flash_assisted"`
+}
+
+// BombPlantedEvent is fired when the bomb is planted.
+type BombPlantedEvent struct {
+	MapEvent
+	RoundNumber int    `json:"round_number"`
+	PlayerSteam string `json:"player_steamid"`
+	Bombsite    string `json:"bombsite"`
+}
+
+// BombDefusedEvent is fired when the bomb is defused.
+type BombDefusedEvent struct {
+	MapEvent
+	RoundNumber int    `json:"round_number"`
+	PlayerSteam string `json:"player_steamid"`
+	Bombsite    string `json:"bombsite"`
+}
+
+// SeriesEndEvent is fired once, after the last map-scoped event, when the
+// series finishes parsing.
+type SeriesEndEvent struct {
+	MatchEvent
+	WinnerSide    string `json:"winner_side"`
+	Team1MapScore int    `json:"team1_series_score"`
+	Team2MapScore int    `json:"team2_series_score"`
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// JobStatus is the lifecycle state of a parse job.
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobError   JobStatus = "error"
+)
+
+// defaultParseDeadline bounds how long a single job's download+parse may
+// run before it's cancelled, unless overridden by PARSE_DEADLINE (a
+// time.ParseDuration string, e.g. "90s").
+const defaultParseDeadline = 5 * time.Minute
+
+// defaultJobRetention bounds how long a finished job's result stays in
+// memory before the sweep evicts it, unless overridden by JOB_RETENTION
+// (a time.ParseDuration string, e.g. "1h").
+const defaultJobRetention = 30 * time.Minute
+
+// JobView is the JSON shape returned by GET /jobs/{id}.
+type JobView struct {
+	JobID        string      `json:"jobId"`
+	Status       JobStatus   `json:"status"`
+	Progress     int         `json:"progress"`
+	Result       *ParsedData `json:"result,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	WebhookError string      `json:"webhookError,omitempty"`
+}
+
+// Job tracks one /parse request as it moves through the worker pool.
+type Job struct {
+	id        string
+	request   ParseRequest
+	fullStats bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	mu         sync.Mutex
+	status     JobStatus
+	progress   int
+	result     *ParsedData
+	err        error
+	webhookErr error
+	finishedAt time.Time
+}
+
+func (j *Job) view() JobView {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	view := JobView{JobID: j.id, Status: j.status, Progress: j.progress, Result: j.result}
+	if j.err != nil {
+		view.Error = j.err.Error()
+	}
+	if j.webhookErr != nil {
+		view.WebhookError = j.webhookErr.Error()
+	}
+	return view
+}
+
+func (j *Job) setStatus(status JobStatus) {
+	j.mu.Lock()
+	j.status = status
+	j.mu.Unlock()
+}
+
+func (j *Job) setProgress(progress int) {
+	j.mu.Lock()
+	j.progress = progress
+	j.mu.Unlock()
+}
+
+func (j *Job) succeed(result *ParsedData) {
+	j.mu.Lock()
+	j.status = JobDone
+	j.progress = 100
+	j.result = result
+	j.finishedAt = time.Now()
+	j.mu.Unlock()
+}
+
+func (j *Job) fail(err error) {
+	j.mu.Lock()
+	j.status = JobError
+	j.err = err
+	j.finishedAt = time.Now()
+	j.mu.Unlock()
+}
+
+// finishedBefore reports whether the job reached a terminal state (done or
+// errored) before cutoff. A zero-value finishedAt (still queued/running)
+// never matches.
+func (j *Job) finishedBefore(cutoff time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.finishedAt.IsZero() && j.finishedAt.Before(cutoff)
+}
+
+func (j *Job) setWebhookError(err error) {
+	j.mu.Lock()
+	j.webhookErr = err
+	j.mu.Unlock()
+}
+
+// JobQueue is a bounded worker pool that runs parse jobs off the request
+// path, so callers poll GET /jobs/{id} instead of blocking on the parse.
+type JobQueue struct {
+	queue chan *Job
+
+	mu   sync.RWMutex
+	byID map[string]*Job
+}
+
+// NewJobQueue starts `workers` goroutines pulling from a queue of size
+// `capacity`, plus a background sweep that evicts finished jobs from byID
+// so a long-running process doesn't retain every ParsedData it ever
+// produced.
+func NewJobQueue(workers, capacity int) *JobQueue {
+	q := &JobQueue{
+		queue: make(chan *Job, capacity),
+		byID:  make(map[string]*Job),
+	}
+
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+
+	go q.sweepLoop(jobRetention(), time.Minute)
+
+	return q
+}
+
+// sweepLoop periodically evicts jobs that finished (done or errored) more
+// than retention ago, the same prune-on-an-interval pattern
+// Store.StartHousekeeping uses for the events table.
+func (q *JobQueue) sweepLoop(retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		q.sweep(retention)
+	}
+}
+
+func (q *JobQueue) sweep(retention time.Duration) {
+	cutoff := time.Now().Add(-retention)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, job := range q.byID {
+		if job.finishedBefore(cutoff) {
+			delete(q.byID, id)
+		}
+	}
+}
+
+// Enqueue registers a new job and adds it to the queue. ok is false when
+// the queue is full and the job was rejected; callers should respond 429.
+func (q *JobQueue) Enqueue(req ParseRequest, fullStats bool) (job *Job, ok bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job = &Job{
+		id:        ulid.Make().String(),
+		request:   req,
+		fullStats: fullStats,
+		ctx:       ctx,
+		cancel:    cancel,
+		status:    JobQueued,
+	}
+
+	select {
+	case q.queue <- job:
+		q.mu.Lock()
+		q.byID[job.id] = job
+		q.mu.Unlock()
+		return job, true
+	default:
+		cancel()
+		return nil, false
+	}
+}
+
+// Get looks up a job by ID.
+func (q *JobQueue) Get(id string) (*Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	job, ok := q.byID[id]
+	return job, ok
+}
+
+// Cancel stops a queued or running job. It returns false if no job with
+// that ID is known.
+func (q *JobQueue) Cancel(id string) bool {
+	job, ok := q.Get(id)
+	if !ok {
+		return false
+	}
+	job.cancel()
+	return true
+}
+
+func (q *JobQueue) work() {
+	for job := range q.queue {
+		q.run(job)
+	}
+}
+
+func (q *JobQueue) run(job *Job) {
+	job.setStatus(JobRunning)
+
+	ctx, cancel := context.WithTimeout(job.ctx, parseDeadline())
+	defer cancel()
+
+	demoData, err := downloadDemo(ctx, job.request.FilePath)
+	if err != nil {
+		job.fail(err)
+		return
+	}
+
+	// A repeat submission of a demo we've already parsed skips the parser
+	// (and the webhook, since the first submission already delivered it).
+	// The cache is keyed by demo content, not by DemoID, so a hit can come
+	// from a different submitter's request; re-stamp the identifiers that
+	// are per-request rather than per-demo before this job claims it.
+	cached, cacheHit := demoCache.Get(ctx, demoData)
+	if cacheHit {
+		cached.DemoID = job.request.DemoID
+	}
+
+	parsed := cached
+	var get5Events []interface{}
+	if !cacheHit {
+		parsed, get5Events, err = parseDemo(ctx, job.request.DemoID, job.request.MatchID, job.request.MapNumber, demoData, job.fullStats, job.setProgress)
+		if err != nil {
+			job.fail(err)
+			return
+		}
+
+		// The parse already succeeded and JobView.Result exists precisely so
+		// a poller doesn't have to depend on the webhook: a webhook outage
+		// is recorded on the job but doesn't fail it or skip persistence/caching.
+		var webhookErr error
+		if webhookMode() == webhookModeStream {
+			webhookErr = sendEventsToWebhook(get5Events)
+		} else {
+			webhookErr = sendToWebhook(parsed)
+		}
+		if webhookErr != nil {
+			log.Printf("Error sending webhook for job %s: %v", job.id, webhookErr)
+			job.setWebhookError(webhookErr)
+		}
+	}
+
+	// Persisted per-request (keyed by this job's MatchID/MapNumber) even on
+	// a cache hit, since the same demo bytes can legitimately back more
+	// than one match record (e.g. a resubmission under a new MatchID).
+	if store != nil {
+		if err := store.Save(ctx, int64(job.request.MatchID), job.request.MapNumber, parsed); err != nil {
+			log.Printf("Error persisting match %d: %v", job.request.MatchID, err)
+		}
+	}
+
+	if !cacheHit {
+		demoCache.Set(ctx, demoData, parsed)
+	}
+	job.succeed(parsed)
+}
+
+func parseDeadline() time.Duration {
+	if raw := os.Getenv("PARSE_DEADLINE"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+	}
+	return defaultParseDeadline
+}
+
+func parserWorkerCount() int {
+	if raw := os.Getenv("PARSER_WORKERS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 4
+}
+
+func jobRetention() time.Duration {
+	if raw := os.Getenv("JOB_RETENTION"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+	}
+	return defaultJobRetention
+}
+
+func parserQueueCapacity() int {
+	if raw := os.Getenv("PARSER_QUEUE_SIZE"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 64
+}
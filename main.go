@@ -2,12 +2,15 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
@@ -16,58 +19,107 @@ import (
 )
 
 type ParseRequest struct {
-	DemoID   string \`json:"demoId"\`
-	FilePath string \`json:"filePath"\`
+	DemoID    string `json:"demoId"`
+	FilePath  string `json:"filePath"`
+	MatchID   int    `json:"matchId"`
+	MapNumber int    `json:"mapNumber"`
 }
 
 type ParsedData struct {
-	DemoID   string      \`json:"demoId"\`
-	Players  []Player    \`json:"players"\`
-	Rounds   []Round     \`json:"rounds"\`
-	Events   []GameEvent \`json:"events"\`
-	Metadata Metadata    \`json:"metadata"\`
+	DemoID   string      `json:"demoId"`
+	Players  []Player    `json:"players"`
+	Rounds   []Round     `json:"rounds"`
+	Events   []GameEvent `json:"events"`
+	Metadata Metadata    `json:"metadata"`
 }
 
 type Player struct {
-	SteamID string                 \`json:"steamId"\`
-	Name    string                 \`json:"name"\`
-	Team    string                 \`json:"team"\`
-	Kills   int                    \`json:"kills"\`
-	Deaths  int                    \`json:"deaths"\`
-	Assists int                    \`json:"assists"\`
-	ADR     float64                \`json:"adr"\`
-	HSP     float64                \`json:"hsp"\`
-	KAST    float64                \`json:"kast"\`
-	Rating  float64                \`json:"rating"\`
-	Stats   map[string]interface{} \`json:"stats"\`
+	SteamID string                 `json:"steamId"`
+	Name    string                 `json:"name"`
+	Team    string                 `json:"team"`
+	Kills   int                    `json:"kills"`
+	Deaths  int                    `json:"deaths"`
+	Assists int                    `json:"assists"`
+	ADR     float64                `json:"adr"`
+	HSP     float64                `json:"hsp"`
+	KAST    float64                `json:"kast"`
+	Rating  float64                `json:"rating"`
+	Stats   map[string]interface{} `json:"stats"`
+
+	// The following are always populated by economyEngine.finalize,
+	// regardless of ?stats=full, since they're cheap running totals rather
+	// than the per-round bookkeeping ADR/KAST/Rating need.
+	Damage         int `json:"damage"`
+	HeadshotKills  int `json:"headshotKills"`
+	FlashAssists   int `json:"flashAssists"`
+	MVPs           int `json:"mvps"`
+	Clutches1vN    int `json:"clutches1vN"`
+	FirstKills     int `json:"firstKills"`
+	FirstDeaths    int `json:"firstDeaths"`
+	UtilityDamage  int `json:"utilityDamage"`
+	EnemiesFlashed int `json:"enemiesFlashed"`
+
+	// PerRound is only populated when /parse is called with ?stats=full.
+	PerRound []RoundRating `json:"perRound,omitempty"`
 }
 
 type Round struct {
-	RoundNumber     int                    \`json:"roundNumber"\`
-	WinnerSide      string                 \`json:"winnerSide"\`
-	WinReason       string                 \`json:"winReason"\`
-	CTScore         int                    \`json:"ctScore"\`
-	TScore          int                    \`json:"tScore"\`
-	DurationSeconds int                    \`json:"durationSeconds"\`
-	RoundData       map[string]interface{} \`json:"roundData"\`
+	RoundNumber     int                         `json:"roundNumber"`
+	WinnerSide      string                      `json:"winnerSide"`
+	WinReason       string                      `json:"winReason"`
+	CTScore         int                         `json:"ctScore"`
+	TScore          int                         `json:"tScore"`
+	DurationSeconds int                         `json:"durationSeconds"`
+	RoundData       map[string]interface{}      `json:"roundData"`
+	Economy         RoundEconomy                `json:"economy"`
+	PlayerStats     map[string]PlayerRoundStats `json:"playerStats"`
 }
 
 type GameEvent struct {
-	EventType   string                 \`json:"eventType"\`
-	Tick        int                    \`json:"tick"\`
-	RoundNumber int                    \`json:"roundNumber"\`
-	EventData   map[string]interface{} \`json:"eventData"\`
+	EventType   string                 `json:"eventType"`
+	Tick        int                    `json:"tick"`
+	RoundNumber int                    `json:"roundNumber"`
+	EventData   map[string]interface{} `json:"eventData"`
 }
 
 type Metadata struct {
-	MapName  string \`json:"mapName"\`
-	Duration int    \`json:"duration"\`
-	TickRate int    \`json:"tickRate"\`
+	MapName  string `json:"mapName"`
+	Duration int    `json:"duration"`
+	TickRate int    `json:"tickRate"`
 }
 
+var (
+	jobQueue   *JobQueue
+	demoCache  *DemoCache
+	rateLimits *parseRateLimiter
+	store      *Store
+)
+
 func main() {
-	http.HandleFunc("/parse", parseHandler)
-	http.HandleFunc("/health", healthHandler)
+	sqldebug := flag.Bool("sqldebug", false, "log every SQL statement the store runs")
+	flag.Parse()
+
+	jobQueue = NewJobQueue(parserWorkerCount(), parserQueueCapacity())
+	demoCache = newDemoCache()
+	rateLimits = newParseRateLimiter()
+
+	var err error
+	store, err = newStore(os.Getenv("DATABASE_URL"), *sqldebug)
+	if err != nil {
+		log.Fatalf("Error connecting to store: %v", err)
+	}
+	if store != nil {
+		store.StartHousekeeping(context.Background(), eventRetention(), time.Hour)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /parse", enqueueParseHandler)
+	mux.HandleFunc("GET /jobs/{id}", getJobHandler)
+	mux.HandleFunc("DELETE /jobs/{id}", cancelJobHandler)
+	mux.HandleFunc("GET /matches/{id}", getMatchHandler)
+	mux.HandleFunc("GET /players/{steamId}/matches", getPlayerMatchesHandler)
+	mux.HandleFunc("GET /matches/{id}/rounds/{n}/events", getMatchRoundEventsHandler)
+	mux.HandleFunc("GET /health", healthHandler)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -75,70 +127,158 @@ func main() {
 	}
 
 	log.Printf("Demo parser service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := http.ListenAndServe(":"+port, mux); err != nil {
 		log.Fatal(err)
 	}
 }
 
+func eventRetention() time.Duration {
+	if raw := os.Getenv("EVENT_RETENTION"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+	}
+	return 30 * 24 * time.Hour
+}
+
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
-func parseHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
+// enqueueParseHandler accepts a parse request and hands it to the job
+// queue instead of parsing inline, so a burst of demos doesn't serialize
+// behind a single 2-minute parse.
+func enqueueParseHandler(w http.ResponseWriter, r *http.Request) {
 	var req ParseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("Parsing demo: %s", req.DemoID)
+	if !rateLimits.Allow(r.RemoteAddr, req.DemoID) {
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	// The Get5 stream embeds KAST/ADR/Rating regardless of query params, so
+	// stream mode forces full stats on even if the caller didn't ask for them.
+	fullStats := r.URL.Query().Get("stats") == "full" || webhookMode() == webhookModeStream
+
+	job, ok := jobQueue.Enqueue(req, fullStats)
+	if !ok {
+		http.Error(w, "parser queue is full", http.StatusTooManyRequests)
+		return
+	}
+
+	log.Printf("Queued demo %s as job %s", req.DemoID, job.id)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(JobView{JobID: job.id, Status: JobQueued})
+}
+
+func getJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := jobQueue.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(job.view())
+}
+
+func cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	if !jobQueue.Cancel(r.PathValue("id")) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getMatchHandler(w http.ResponseWriter, r *http.Request) {
+	if store == nil {
+		http.Error(w, "persistent store not configured", http.StatusServiceUnavailable)
+		return
+	}
 
-	// Download demo from Supabase Storage
-	demoData, err := downloadDemo(req.FilePath)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
-		log.Printf("Error downloading demo: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	match, err := store.GetMatch(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(match)
+}
+
+func getPlayerMatchesHandler(w http.ResponseWriter, r *http.Request) {
+	if store == nil {
+		http.Error(w, "persistent store not configured", http.StatusServiceUnavailable)
 		return
 	}
 
-	// Parse demo
-	parsedData, err := parseDemo(req.DemoID, demoData)
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	matches, err := store.GetPlayerMatches(r.Context(), r.PathValue("steamId"), limit)
 	if err != nil {
-		log.Printf("Error parsing demo: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Send to webhook
-	if err := sendToWebhook(parsedData); err != nil {
-		log.Printf("Error sending to webhook: %v", err)
+	json.NewEncoder(w).Encode(matches)
+}
+
+func getMatchRoundEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if store == nil {
+		http.Error(w, "persistent store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	matchID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	roundNumber, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil {
+		http.Error(w, "invalid round number", http.StatusBadRequest)
+		return
+	}
+
+	events, err := store.GetMatchRoundEvents(r.Context(), matchID, roundNumber, r.URL.Query().Get("type"))
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	json.NewEncoder(w).Encode(events)
 }
 
-func downloadDemo(filePath string) ([]byte, error) {
+func downloadDemo(ctx context.Context, filePath string) ([]byte, error) {
 	supabaseURL := os.Getenv("SUPABASE_URL")
 	serviceRoleKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
 
 	url := fmt.Sprintf("%s/storage/v1/object/demos/%s", supabaseURL, filePath)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+serviceRoleKey)
 
-	client := &http.Client{Timeout: 2 * time.Minute}
+	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -152,12 +292,12 @@ func downloadDemo(filePath string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-func parseDemo(demoID string, data []byte) (*ParsedData, error) {
+func parseDemo(ctx context.Context, demoID string, matchID int, mapNumber int, data []byte, fullStats bool, reportProgress func(int)) (*ParsedData, []interface{}, error) {
 	parsed := &ParsedData{
-		DemoID:  demoID,
-		Players: []Player{},
-		Rounds:  []Round{},
-		Events:  []GameEvent{},
+		DemoID:   demoID,
+		Players:  []Player{},
+		Rounds:   []Round{},
+		Events:   []GameEvent{},
 		Metadata: Metadata{},
 	}
 
@@ -166,8 +306,27 @@ func parseDemo(demoID string, data []byte) (*ParsedData, error) {
 	currentRound := 0
 	startTick := 0
 
+	var get5Events []interface{}
+	get5Events = append(get5Events, SeriesInitEvent{
+		MatchEvent: MatchEvent{Event: "series_init", MatchID: matchID},
+		NumMaps:    1,
+	})
+
 	p := demoinfocs.NewParser(bytes.NewReader(data))
 
+	var stats *statsEngine
+	if fullStats {
+		stats = newStatsEngine(p, playerStats)
+		stats.attach()
+	}
+
+	economy := newEconomyEngine(p, playerStats)
+	economy.attach()
+
+	mapEvent := func(event string) MapEvent {
+		return MapEvent{MatchEvent: MatchEvent{Event: event, MatchID: matchID}, MapNumber: mapNumber}
+	}
+
 	// Register event handlers
 	p.RegisterEventHandler(func(e events.RoundStart) {
 		currentRound++
@@ -176,14 +335,32 @@ func parseDemo(demoID string, data []byte) (*ParsedData, error) {
 
 	p.RegisterEventHandler(func(e events.RoundEnd) {
 		gs := p.GameState()
+		winnerSide := teamString(e.Winner)
+		ctScore := gs.TeamCounterTerrorists().Score()
+		tScore := gs.TeamTerrorists().Score()
+		roundTime := (p.GameState().IngameTick() - startTick) / 128
+		roundEconomy, roundPlayerStats := economy.snapshotRound(winnerSide)
+
 		parsed.Rounds = append(parsed.Rounds, Round{
 			RoundNumber:     currentRound,
-			WinnerSide:      e.Winner.String(),
-			WinReason:       e.Reason.String(),
-			CTScore:         gs.TeamCounterTerrorists().Score(),
-			TScore:          gs.TeamTerrorists().Score(),
-			DurationSeconds: (p.GameState().IngameTick() - startTick) / 128,
+			WinnerSide:      winnerSide,
+			WinReason:       roundEndReasonString(e.Reason),
+			CTScore:         ctScore,
+			TScore:          tScore,
+			DurationSeconds: roundTime,
 			RoundData:       map[string]interface{}{},
+			Economy:         roundEconomy,
+			PlayerStats:     roundPlayerStats,
+		})
+
+		get5Events = append(get5Events, RoundEndEvent{
+			MapEvent:    mapEvent("round_end"),
+			RoundNumber: currentRound,
+			RoundTime:   roundTime,
+			WinnerSide:  winnerSide,
+			Reason:      roundEndReasonString(e.Reason),
+			Team1Score:  tScore,
+			Team2Score:  ctScore,
 		})
 	})
 
@@ -203,11 +380,11 @@ func parseDemo(demoID string, data []byte) (*ParsedData, error) {
 
 		// Store kill event
 		eventData := map[string]interface{}{
-			"killer":      getPlayerName(e.Killer),
-			"victim":      getPlayerName(e.Victim),
-			"weapon":      e.Weapon.String(),
-			"isHeadshot":  e.IsHeadshot,
-			"penetrated":  e.PenetratedObjects,
+			"killer":     getPlayerName(e.Killer),
+			"victim":     getPlayerName(e.Victim),
+			"weapon":     e.Weapon.String(),
+			"isHeadshot": e.IsHeadshot,
+			"penetrated": e.PenetratedObjects,
 		}
 
 		parsed.Events = append(parsed.Events, GameEvent{
@@ -216,27 +393,146 @@ func parseDemo(demoID string, data []byte) (*ParsedData, error) {
 			RoundNumber: currentRound,
 			EventData:   eventData,
 		})
+
+		get5Events = append(get5Events, PlayerDeathEvent{
+			MapEvent:      mapEvent("player_death"),
+			RoundNumber:   currentRound,
+			VictimSteamID: steamIDString(e.Victim),
+			AttackerSteam: steamIDString(e.Killer),
+			AssisterSteam: steamIDString(e.Assister),
+			Weapon:        e.Weapon.String(),
+			Headshot:      e.IsHeadshot,
+			FlashAssisted: e.AssistedFlash,
+		})
+	})
+
+	p.RegisterEventHandler(func(e events.BombPlanted) {
+		get5Events = append(get5Events, BombPlantedEvent{
+			MapEvent:    mapEvent("bomb_planted"),
+			RoundNumber: currentRound,
+			PlayerSteam: steamIDString(e.Player),
+			Bombsite:    string(e.Site),
+		})
+	})
+
+	p.RegisterEventHandler(func(e events.BombDefused) {
+		get5Events = append(get5Events, BombDefusedEvent{
+			MapEvent:    mapEvent("bomb_defused"),
+			RoundNumber: currentRound,
+			PlayerSteam: steamIDString(e.Player),
+			Bombsite:    string(e.Site),
+		})
 	})
 
+	// Honor the caller's deadline/cancellation: abort the in-progress parse
+	// as soon as the context is done, the same deadline-propagation pattern
+	// downloadDemo uses for the HTTP request.
+	stopCancelWatch := context.AfterFunc(ctx, p.Cancel)
+	defer stopCancelWatch()
+
+	if reportProgress != nil {
+		progressDone := make(chan struct{})
+		defer close(progressDone)
+
+		go func() {
+			ticker := time.NewTicker(500 * time.Millisecond)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					reportProgress(int(p.Progress() * 100))
+				case <-progressDone:
+					return
+				}
+			}
+		}()
+	}
+
 	// Parse the demo
 	if err := p.ParseToEnd(); err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, err
+	}
+
+	if stats != nil {
+		stats.finalize()
 	}
+	economy.finalize()
 
 	// Convert player stats
 	for _, player := range playerStats {
 		parsed.Players = append(parsed.Players, *player)
 	}
 
-	// Set metadata
-	header := p.Header()
+	// Set metadata. The demoinfocs-golang v5 Parser doesn't expose the demo
+	// header publicly, so map name isn't available here; duration and tick
+	// rate come from the parser's own bookkeeping instead.
 	parsed.Metadata = Metadata{
-		MapName:  header.MapName,
-		Duration: int(p.Header().PlaybackTime.Seconds()),
-		TickRate: int(p.Header().FrameRate()),
+		Duration: int(p.CurrentTime().Seconds()),
+		TickRate: int(p.TickRate()),
+	}
+
+	gs := p.GameState()
+	team1, team2 := buildGet5Teams(parsed.Players, gs.TeamTerrorists().Score(), gs.TeamCounterTerrorists().Score())
+	winnerSide := "T"
+	if gs.TeamCounterTerrorists().Score() > gs.TeamTerrorists().Score() {
+		winnerSide = "CT"
+	}
+
+	get5Events = append(get5Events, MapResultEvent{
+		MapEvent:   mapEvent("map_result"),
+		WinnerSide: winnerSide,
+		Team1:      team1,
+		Team2:      team2,
+	})
+
+	get5Events = append(get5Events, SeriesEndEvent{
+		MatchEvent:    MatchEvent{Event: "series_end", MatchID: matchID},
+		WinnerSide:    winnerSide,
+		Team1MapScore: gs.TeamTerrorists().Score(),
+		Team2MapScore: gs.TeamCounterTerrorists().Score(),
+	})
+
+	return parsed, get5Events, nil
+}
+
+// buildGet5Teams splits the parsed players into T/CT Get5StatsTeam blocks.
+func buildGet5Teams(players []Player, tScore, ctScore int) (team1, team2 Get5StatsTeam) {
+	team1 = Get5StatsTeam{Side: "T", Score: tScore}
+	team2 = Get5StatsTeam{Side: "CT", Score: ctScore}
+
+	for _, player := range players {
+		statsPlayer := Get5StatsPlayer{
+			SteamID:        player.SteamID,
+			Name:           player.Name,
+			Kills:          player.Kills,
+			Deaths:         player.Deaths,
+			Assists:        player.Assists,
+			Damage:         player.Damage,
+			HeadshotKills:  player.HeadshotKills,
+			FlashAssists:   player.FlashAssists,
+			KAST:           player.KAST,
+			ADR:            player.ADR,
+			MVPs:           player.MVPs,
+			Clutches1vN:    player.Clutches1vN,
+			FirstKills:     player.FirstKills,
+			FirstDeaths:    player.FirstDeaths,
+			UtilityDamage:  player.UtilityDamage,
+			EnemiesFlashed: player.EnemiesFlashed,
+		}
+
+		switch player.Team {
+		case "T":
+			team1.Players = append(team1.Players, statsPlayer)
+		case "CT":
+			team2.Players = append(team2.Players, statsPlayer)
+		}
 	}
 
-	return parsed, nil
+	return team1, team2
 }
 
 func getOrCreatePlayer(stats map[uint64]*Player, p *common.Player) *Player {
@@ -273,11 +569,99 @@ func getPlayerName(p *common.Player) string {
 	return p.Name
 }
 
+func steamIDString(p *common.Player) string {
+	if p == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", p.SteamID64)
+}
+
+func teamString(t common.Team) string {
+	switch t {
+	case common.TeamTerrorists:
+		return "T"
+	case common.TeamCounterTerrorists:
+		return "CT"
+	case common.TeamSpectators:
+		return "spectator"
+	default:
+		return "unassigned"
+	}
+}
+
+func roundEndReasonString(r events.RoundEndReason) string {
+	switch r {
+	case events.RoundEndReasonTargetBombed:
+		return "target_bombed"
+	case events.RoundEndReasonVIPEscaped:
+		return "vip_escaped"
+	case events.RoundEndReasonVIPKilled:
+		return "vip_killed"
+	case events.RoundEndReasonTerroristsEscaped:
+		return "terrorists_escaped"
+	case events.RoundEndReasonCTStoppedEscape:
+		return "ct_stopped_escape"
+	case events.RoundEndReasonTerroristsStopped:
+		return "terrorists_stopped"
+	case events.RoundEndReasonBombDefused:
+		return "bomb_defused"
+	case events.RoundEndReasonCTWin:
+		return "ct_win"
+	case events.RoundEndReasonTerroristsWin:
+		return "terrorists_win"
+	case events.RoundEndReasonDraw:
+		return "draw"
+	case events.RoundEndReasonHostagesRescued:
+		return "hostages_rescued"
+	case events.RoundEndReasonTargetSaved:
+		return "target_saved"
+	case events.RoundEndReasonHostagesNotRescued:
+		return "hostages_not_rescued"
+	case events.RoundEndReasonTerroristsNotEscaped:
+		return "terrorists_not_escaped"
+	case events.RoundEndReasonVIPNotEscaped:
+		return "vip_not_escaped"
+	case events.RoundEndReasonGameStart:
+		return "game_start"
+	default:
+		return "still_in_progress"
+	}
+}
+
+// Webhook modes for WEBHOOK_MODE: "batch" posts one aggregated ParsedData
+// blob (the original behavior), "stream" posts the Get5/MatchZy-style
+// events one at a time, in order, so downstream consumers built for Get5
+// can be plugged in unchanged.
+const (
+	webhookModeBatch  = "batch"
+	webhookModeStream = "stream"
+)
+
+func webhookMode() string {
+	if os.Getenv("WEBHOOK_MODE") == webhookModeStream {
+		return webhookModeStream
+	}
+	return webhookModeBatch
+}
+
 func sendToWebhook(data *ParsedData) error {
+	return postWebhookPayload(data)
+}
+
+func sendEventsToWebhook(get5Events []interface{}) error {
+	for _, event := range get5Events {
+		if err := postWebhookPayload(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func postWebhookPayload(payload interface{}) error {
 	webhookURL := os.Getenv("WEBHOOK_URL")
 	secret := os.Getenv("GO_SERVICE_SECRET")
 
-	jsonData, err := json.Marshal(data)
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
@@ -303,4 +687,4 @@ func sendToWebhook(data *ParsedData) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// defaultLimiterIdleTTL bounds how long a per-key limiter sits idle before
+// the sweep evicts it, unless overridden by RATE_LIMIT_IDLE_TTL (a
+// time.ParseDuration string, e.g. "10m"). byDemoID is keyed by a value
+// that's unique per submission by design, so without this its map would
+// grow by one permanent entry for every demo the service ever parses.
+const defaultLimiterIdleTTL = 10 * time.Minute
+
+// keyedLimiter lazily creates one token-bucket limiter per key (remote IP
+// or DemoID), so a single noisy caller can't starve everyone else's
+// budget. A background sweep evicts limiters idle past idleTTL so the map
+// doesn't grow without bound for keys that are only ever seen once.
+type keyedLimiter struct {
+	rps     rate.Limit
+	burst   int
+	idleTTL time.Duration
+
+	mu       sync.Mutex
+	limiters map[string]*limiterEntry
+}
+
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+func newKeyedLimiter(rps float64, burst int, idleTTL time.Duration) *keyedLimiter {
+	k := &keyedLimiter{
+		rps:      rate.Limit(rps),
+		burst:    burst,
+		idleTTL:  idleTTL,
+		limiters: make(map[string]*limiterEntry),
+	}
+
+	go k.sweepLoop(idleTTL)
+
+	return k
+}
+
+func (k *keyedLimiter) Allow(key string) bool {
+	k.mu.Lock()
+	entry, ok := k.limiters[key]
+	if !ok {
+		entry = &limiterEntry{limiter: rate.NewLimiter(k.rps, k.burst)}
+		k.limiters[key] = entry
+	}
+	entry.lastUsed = time.Now()
+	limiter := entry.limiter
+	k.mu.Unlock()
+
+	return limiter.Allow()
+}
+
+// sweepLoop periodically evicts limiters idle past idleTTL, the same
+// prune-on-an-interval pattern Store.StartHousekeeping uses for the
+// events table.
+func (k *keyedLimiter) sweepLoop(idleTTL time.Duration) {
+	ticker := time.NewTicker(idleTTL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		k.sweep(idleTTL)
+	}
+}
+
+func (k *keyedLimiter) sweep(idleTTL time.Duration) {
+	cutoff := time.Now().Add(-idleTTL)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for key, entry := range k.limiters {
+		if entry.lastUsed.Before(cutoff) {
+			delete(k.limiters, key)
+		}
+	}
+}
+
+// parseRateLimiter layers a global bucket in front of /parse with a
+// per-IP and a per-DemoID bucket, so one share-code or one caller can't
+// monopolize the global budget.
+type parseRateLimiter struct {
+	global   *rate.Limiter
+	byIP     *keyedLimiter
+	byDemoID *keyedLimiter
+}
+
+// newParseRateLimiter reads its limits from RATE_LIMIT_* env vars, each
+// falling back to a sane default.
+func newParseRateLimiter() *parseRateLimiter {
+	idleTTL := envDuration("RATE_LIMIT_IDLE_TTL", defaultLimiterIdleTTL)
+	return &parseRateLimiter{
+		global:   rate.NewLimiter(rate.Limit(envFloat("RATE_LIMIT_GLOBAL_RPS", 20)), envInt("RATE_LIMIT_GLOBAL_BURST", 40)),
+		byIP:     newKeyedLimiter(envFloat("RATE_LIMIT_IP_RPS", 1), envInt("RATE_LIMIT_IP_BURST", 3), idleTTL),
+		byDemoID: newKeyedLimiter(envFloat("RATE_LIMIT_DEMO_RPS", 1), envInt("RATE_LIMIT_DEMO_BURST", 2), idleTTL),
+	}
+}
+
+// Allow reports whether a /parse request from remoteAddr for demoID may
+// proceed. All three buckets must have capacity.
+func (l *parseRateLimiter) Allow(remoteAddr, demoID string) bool {
+	return l.global.Allow() && l.byIP.Allow(clientIP(remoteAddr)) && l.byDemoID.Allow(demoID)
+}
+
+func clientIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func envFloat(key string, fallback float64) float64 {
+	if raw := os.Getenv(key); raw != "" {
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			return v
+		}
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if raw := os.Getenv(key); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil {
+			return v
+		}
+	}
+	return fallback
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if raw := os.Getenv(key); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		want       string
+	}{
+		{"ipv4 with port", "203.0.113.5:54321", "203.0.113.5"},
+		{"ipv6 with port", "[2001:db8::1]:8080", "2001:db8::1"},
+		{"no port", "203.0.113.5", "203.0.113.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientIP(tt.remoteAddr); got != tt.want {
+				t.Errorf("clientIP(%q) = %q, want %q", tt.remoteAddr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyedLimiterSweepEvictsIdleKeys(t *testing.T) {
+	k := newKeyedLimiter(1, 1, time.Hour)
+
+	k.Allow("demo-1")
+	k.Allow("demo-2")
+	k.mu.Lock()
+	k.limiters["demo-1"].lastUsed = time.Now().Add(-2 * time.Hour)
+	k.mu.Unlock()
+
+	k.sweep(time.Hour)
+
+	k.mu.Lock()
+	_, staleStillPresent := k.limiters["demo-1"]
+	_, freshStillPresent := k.limiters["demo-2"]
+	k.mu.Unlock()
+
+	if staleStillPresent {
+		t.Error("sweep should have evicted a limiter idle past idleTTL")
+	}
+	if !freshStillPresent {
+		t.Error("sweep should not evict a limiter used within idleTTL")
+	}
+}
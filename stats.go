@@ -0,0 +1,210 @@
+package main
+
+import (
+	"time"
+
+	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+)
+
+// tradeWindow is how long after a player's death a teammate's kill on the
+// killer still counts as a trade for KAST purposes.
+const tradeWindow = 5 * time.Second
+
+// RoundRating is a single player's Rating 2.0 inputs and output for one
+// round, so callers can request the rating as a time series instead of
+// just the match average.
+type RoundRating struct {
+	RoundNumber int     `json:"roundNumber"`
+	Kills       int     `json:"kills"`
+	Assists     int     `json:"assists"`
+	Damage      int     `json:"damage"`
+	KAST        bool    `json:"kast"`
+	Rating      float64 `json:"rating"`
+}
+
+// deathRecord tracks a death within the current round so later kills can
+// be checked against the trade window.
+type deathRecord struct {
+	victim uint64
+	killer uint64
+	team   common.Team
+	tick   int
+}
+
+// statsEngine registers the handlers that back Player.ADR/HSP/KAST/Rating.
+// It's only attached when the caller asks for /parse?stats=full, since the
+// extra per-round bookkeeping isn't free and most callers just want
+// kills/deaths/assists.
+type statsEngine struct {
+	p           demoinfocs.Parser
+	playerStats map[uint64]*Player
+
+	roundDamage  map[uint64]int
+	roundKills   map[uint64]int
+	roundAssists map[uint64]int
+	roundAlive   map[uint64]bool
+	roundTraded  map[uint64]bool
+	roundDeaths  []deathRecord
+
+	totalKills     map[uint64]int
+	totalDeaths    map[uint64]int
+	totalAssists   map[uint64]int
+	totalDamage    map[uint64]int
+	totalHeadshots map[uint64]int
+	kastRounds     map[uint64]int
+	perRound       map[uint64][]RoundRating
+	roundsPlayed   int
+}
+
+func newStatsEngine(p demoinfocs.Parser, playerStats map[uint64]*Player) *statsEngine {
+	return &statsEngine{
+		p:              p,
+		playerStats:    playerStats,
+		totalKills:     make(map[uint64]int),
+		totalDeaths:    make(map[uint64]int),
+		totalAssists:   make(map[uint64]int),
+		totalDamage:    make(map[uint64]int),
+		totalHeadshots: make(map[uint64]int),
+		kastRounds:     make(map[uint64]int),
+		perRound:       make(map[uint64][]RoundRating),
+	}
+}
+
+func (s *statsEngine) attach() {
+	s.p.RegisterEventHandler(s.onRoundStart)
+	s.p.RegisterEventHandler(s.onPlayerHurt)
+	s.p.RegisterEventHandler(s.onKill)
+	s.p.RegisterEventHandler(s.onRoundEnd)
+}
+
+func (s *statsEngine) onRoundStart(e events.RoundStart) {
+	s.roundDamage = make(map[uint64]int)
+	s.roundKills = make(map[uint64]int)
+	s.roundAssists = make(map[uint64]int)
+	s.roundTraded = make(map[uint64]bool)
+	s.roundDeaths = nil
+
+	s.roundAlive = make(map[uint64]bool, len(s.playerStats))
+	for steamID := range s.playerStats {
+		s.roundAlive[steamID] = true
+	}
+}
+
+func (s *statsEngine) onPlayerHurt(e events.PlayerHurt) {
+	if e.Attacker == nil || e.Player == nil || e.Attacker.SteamID64 == e.Player.SteamID64 || e.Attacker.Team == e.Player.Team {
+		return
+	}
+	s.roundDamage[e.Attacker.SteamID64] += e.HealthDamageTaken
+	s.totalDamage[e.Attacker.SteamID64] += e.HealthDamageTaken
+}
+
+func (s *statsEngine) onKill(e events.Kill) {
+	tick := s.p.GameState().IngameTick()
+
+	if e.Victim != nil {
+		s.roundAlive[e.Victim.SteamID64] = false
+		s.totalDeaths[e.Victim.SteamID64]++
+	}
+
+	if e.Killer != nil && e.Victim != nil {
+		s.roundKills[e.Killer.SteamID64]++
+		s.totalKills[e.Killer.SteamID64]++
+		if e.IsHeadshot {
+			s.totalHeadshots[e.Killer.SteamID64]++
+		}
+
+		// A kill that avenges a teammate's death by the same victim within
+		// the trade window counts toward that teammate's KAST.
+		for _, d := range s.roundDeaths {
+			if d.killer == e.Victim.SteamID64 && d.team == e.Killer.Team && withinTradeWindow(s.p, d.tick, tick) {
+				s.roundTraded[d.victim] = true
+			}
+		}
+
+		s.roundDeaths = append(s.roundDeaths, deathRecord{
+			victim: e.Victim.SteamID64,
+			killer: e.Killer.SteamID64,
+			team:   e.Victim.Team,
+			tick:   tick,
+		})
+	}
+
+	if e.Assister != nil {
+		s.roundAssists[e.Assister.SteamID64]++
+		s.totalAssists[e.Assister.SteamID64]++
+	}
+}
+
+// withinTradeWindow reports whether killTick falls within tradeWindow of
+// deathTick, shared by statsEngine (for KAST) and economyEngine (for
+// tradeKills/tradedDeaths).
+func withinTradeWindow(p demoinfocs.Parser, deathTick, killTick int) bool {
+	rate := p.TickRate()
+	if rate <= 0 {
+		rate = 128
+	}
+	return float64(killTick-deathTick)/rate <= tradeWindow.Seconds()
+}
+
+func (s *statsEngine) onRoundEnd(e events.RoundEnd) {
+	s.roundsPlayed++
+	roundNumber := s.roundsPlayed
+
+	for steamID := range s.playerStats {
+		kills := s.roundKills[steamID]
+		assists := s.roundAssists[steamID]
+		damage := s.roundDamage[steamID]
+		kast := kills > 0 || assists > 0 || s.roundAlive[steamID] || s.roundTraded[steamID]
+
+		if kast {
+			s.kastRounds[steamID]++
+		}
+
+		impact := 2.13*float64(kills) + 0.42*float64(assists) - 0.41
+		kastValue := 0.0
+		if kast {
+			kastValue = 100.0
+		}
+		dpr := 0.0
+		if !s.roundAlive[steamID] {
+			dpr = 1.0
+		}
+		rating := 0.0073*kastValue + 0.3591*float64(kills) - 0.5329*dpr + 0.2372*impact + 0.0032*float64(damage) + 0.1587
+
+		s.perRound[steamID] = append(s.perRound[steamID], RoundRating{
+			RoundNumber: roundNumber,
+			Kills:       kills,
+			Assists:     assists,
+			Damage:      damage,
+			KAST:        kast,
+			Rating:      rating,
+		})
+	}
+}
+
+// finalize computes the match-average ADR/HSP/KAST/Rating for every
+// tracked player and attaches the per-round series.
+func (s *statsEngine) finalize() {
+	if s.roundsPlayed == 0 {
+		return
+	}
+
+	rounds := float64(s.roundsPlayed)
+
+	for steamID, player := range s.playerStats {
+		kpr := float64(s.totalKills[steamID]) / rounds
+		dpr := float64(s.totalDeaths[steamID]) / rounds
+		apr := float64(s.totalAssists[steamID]) / rounds
+		impact := 2.13*kpr + 0.42*apr - 0.41
+
+		player.ADR = float64(s.totalDamage[steamID]) / rounds
+		player.KAST = float64(s.kastRounds[steamID]) / rounds * 100
+		if s.totalKills[steamID] > 0 {
+			player.HSP = float64(s.totalHeadshots[steamID]) / float64(s.totalKills[steamID]) * 100
+		}
+		player.Rating = 0.0073*player.KAST + 0.3591*kpr - 0.5329*dpr + 0.2372*impact + 0.0032*player.ADR + 0.1587
+		player.PerRound = s.perRound[steamID]
+	}
+}
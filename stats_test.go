@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
+)
+
+const floatTolerance = 1e-9
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < floatTolerance
+}
+
+func TestWithinTradeWindow(t *testing.T) {
+	p := demoinfocs.NewParser(bytes.NewReader(make([]byte, 4096)))
+
+	// At 128 tick (the fallback rate used when the parser hasn't seen a
+	// header yet), tradeWindow (5s) is 640 ticks.
+	tests := []struct {
+		name      string
+		deathTick int
+		killTick  int
+		want      bool
+	}{
+		{"immediate trade", 100, 105, true},
+		{"right at the edge", 100, 740, true},
+		{"just past the edge", 100, 741, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withinTradeWindow(p, tt.deathTick, tt.killTick); got != tt.want {
+				t.Errorf("withinTradeWindow(%d, %d) = %v, want %v", tt.deathTick, tt.killTick, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRatingFormula locks down the Rating 2.0 coefficients in onRoundEnd/
+// finalize against hand-computed values, so a sign flip or reordering in
+// either copy of the formula fails loudly instead of shipping silently.
+func TestRatingFormula(t *testing.T) {
+	const steamID = uint64(1)
+	playerStats := map[uint64]*Player{steamID: {SteamID: "1"}}
+
+	s := newStatsEngine(nil, playerStats)
+	s.roundsPlayed = 2
+	s.totalKills[steamID] = 3
+	s.totalDeaths[steamID] = 1
+	s.totalAssists[steamID] = 1
+	s.totalDamage[steamID] = 200
+	s.totalHeadshots[steamID] = 2
+	s.kastRounds[steamID] = 2
+
+	s.finalize()
+
+	player := playerStats[steamID]
+
+	wantADR := 100.0
+	if !approxEqual(player.ADR, wantADR) {
+		t.Errorf("ADR = %v, want %v", player.ADR, wantADR)
+	}
+
+	wantKAST := 100.0
+	if !approxEqual(player.KAST, wantKAST) {
+		t.Errorf("KAST = %v, want %v", player.KAST, wantKAST)
+	}
+
+	wantHSP := 2.0 / 3.0 * 100
+	if !approxEqual(player.HSP, wantHSP) {
+		t.Errorf("HSP = %v, want %v", player.HSP, wantHSP)
+	}
+
+	kpr := 1.5
+	dpr := 0.5
+	apr := 0.5
+	impact := 2.13*kpr + 0.42*apr - 0.41
+	wantRating := 0.0073*wantKAST + 0.3591*kpr - 0.5329*dpr + 0.2372*impact + 0.0032*wantADR + 0.1587
+	if !approxEqual(player.Rating, wantRating) {
+		t.Errorf("Rating = %v, want %v", player.Rating, wantRating)
+	}
+}
+
+func TestRatingFormulaNoRounds(t *testing.T) {
+	playerStats := map[uint64]*Player{1: {SteamID: "1"}}
+	s := newStatsEngine(nil, playerStats)
+
+	s.finalize()
+
+	if playerStats[1].Rating != 0 {
+		t.Errorf("Rating = %v, want 0 when no rounds were played", playerStats[1].Rating)
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// schemaMigrationsTable tracks which of the migrations below have run, so
+// newStore can bring an existing database up to date without re-running
+// (or losing track of) anything already applied.
+const schemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INT PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);`
+
+// migration is one forward-only schema change, applied at most once and
+// recorded in schema_migrations. Append new migrations to the end of the
+// migrations slice below; never edit or remove an already-released one.
+type migration struct {
+	version int
+	stmt    string
+}
+
+var migrations = []migration{
+	{version: 1, stmt: `
+CREATE TABLE IF NOT EXISTS matches (
+	id         BIGSERIAL PRIMARY KEY,
+	demo_id    TEXT NOT NULL,
+	match_id   BIGINT NOT NULL,
+	map_number INT NOT NULL,
+	map_name   TEXT,
+	duration   INT,
+	tick_rate  INT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS players (
+	steam_id TEXT PRIMARY KEY,
+	name     TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS match_players (
+	match_id BIGINT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+	steam_id TEXT NOT NULL REFERENCES players(steam_id),
+	team     TEXT,
+	kills    INT,
+	deaths   INT,
+	assists  INT,
+	adr      DOUBLE PRECISION,
+	hsp      DOUBLE PRECISION,
+	kast     DOUBLE PRECISION,
+	rating   DOUBLE PRECISION,
+	PRIMARY KEY (match_id, steam_id)
+);
+
+CREATE TABLE IF NOT EXISTS rounds (
+	match_id         BIGINT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+	round_number     INT NOT NULL,
+	winner_side      TEXT,
+	win_reason       TEXT,
+	ct_score         INT,
+	t_score          INT,
+	duration_seconds INT,
+	PRIMARY KEY (match_id, round_number)
+);
+
+CREATE TABLE IF NOT EXISTS events (
+	id           BIGSERIAL PRIMARY KEY,
+	match_id     BIGINT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+	round_number INT NOT NULL,
+	event_type   TEXT NOT NULL,
+	tick         INT,
+	event_data   JSONB,
+	created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS events_match_round_idx ON events (match_id, round_number);
+CREATE INDEX IF NOT EXISTS events_created_at_idx ON events (created_at);
+`},
+	{version: 2, stmt: `
+-- Carries Round.Economy and Round.PlayerStats (added alongside economy.go),
+-- which until now were computed but never reached Postgres.
+ALTER TABLE rounds ADD COLUMN IF NOT EXISTS economy JSONB;
+ALTER TABLE rounds ADD COLUMN IF NOT EXISTS player_stats JSONB;
+
+-- Carries the match-cumulative totals added to Player alongside
+-- buildGet5Teams's damage/headshots/MVPs/clutches/first-kill fields.
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS damage INT;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS headshot_kills INT;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS flash_assists INT;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS mvps INT;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS clutches_1vn INT;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS first_kills INT;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS first_deaths INT;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS utility_damage INT;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS enemies_flashed INT;
+`},
+}
+
+// MatchSummary is one row of a player's match history.
+type MatchSummary struct {
+	ID        int64     `json:"id"`
+	DemoID    string    `json:"demoId"`
+	MatchID   int64     `json:"matchId"`
+	MapNumber int       `json:"mapNumber"`
+	MapName   string    `json:"mapName"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store persists parsed demo results to Postgres and serves the read
+// endpoints. It's optional: when DATABASE_URL is unset, newStore returns
+// nil and parseDemo keeps relying solely on the webhook/cache.
+type Store struct {
+	db       *sql.DB
+	sqldebug bool
+}
+
+// newStore opens the pool, applies schema, and returns nil (not an error)
+// when dsn is empty so the service degrades gracefully without Postgres.
+func newStore(dsn string, sqldebug bool) (*Store, error) {
+	if dsn == "" {
+		return nil, nil
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := &Store{db: db, sqldebug: sqldebug}
+	if err := store.applyMigrations(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// applyMigrations brings the database up to date, running any migration
+// not yet recorded in schema_migrations, in order, each in its own
+// transaction so a failure partway through doesn't mark it as applied.
+func (s *Store) applyMigrations() error {
+	if _, err := s.db.Exec(schemaMigrationsTable); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		var applied bool
+		if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version).Scan(&applied); err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(m.stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %d", m.version)
+	}
+
+	return nil
+}
+
+func (s *Store) logQuery(query string, args ...interface{}) {
+	if s.sqldebug {
+		log.Printf("[sqldebug] %s %v", query, args)
+	}
+}
+
+// Save persists one parsed map transactionally: the match row, its
+// rounds, players, match_players and events.
+func (s *Store) Save(ctx context.Context, matchID int64, mapNumber int, parsed *ParsedData) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var dbMatchID int64
+	query := `INSERT INTO matches (demo_id, match_id, map_number, map_name, duration, tick_rate)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	s.logQuery(query, parsed.DemoID, matchID, mapNumber)
+	err = tx.QueryRowContext(ctx, query, parsed.DemoID, matchID, mapNumber,
+		parsed.Metadata.MapName, parsed.Metadata.Duration, parsed.Metadata.TickRate).Scan(&dbMatchID)
+	if err != nil {
+		return err
+	}
+
+	for _, round := range parsed.Rounds {
+		economy, err := json.Marshal(round.Economy)
+		if err != nil {
+			return err
+		}
+		playerStats, err := json.Marshal(round.PlayerStats)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO rounds (match_id, round_number, winner_side, win_reason, ct_score, t_score, duration_seconds, economy, player_stats)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+		s.logQuery(query, dbMatchID, round.RoundNumber)
+		_, err = tx.ExecContext(ctx, query, dbMatchID, round.RoundNumber, round.WinnerSide,
+			round.WinReason, round.CTScore, round.TScore, round.DurationSeconds, economy, playerStats)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, player := range parsed.Players {
+		query := `INSERT INTO players (steam_id, name) VALUES ($1, $2)
+			ON CONFLICT (steam_id) DO UPDATE SET name = EXCLUDED.name`
+		s.logQuery(query, player.SteamID)
+		if _, err := tx.ExecContext(ctx, query, player.SteamID, player.Name); err != nil {
+			return err
+		}
+
+		query = `INSERT INTO match_players (match_id, steam_id, team, kills, deaths, assists, adr, hsp, kast, rating,
+			damage, headshot_kills, flash_assists, mvps, clutches_1vn, first_kills, first_deaths, utility_damage, enemies_flashed)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`
+		s.logQuery(query, dbMatchID, player.SteamID)
+		_, err := tx.ExecContext(ctx, query, dbMatchID, player.SteamID, player.Team, player.Kills,
+			player.Deaths, player.Assists, player.ADR, player.HSP, player.KAST, player.Rating,
+			player.Damage, player.HeadshotKills, player.FlashAssists, player.MVPs, player.Clutches1vN,
+			player.FirstKills, player.FirstDeaths, player.UtilityDamage, player.EnemiesFlashed)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, event := range parsed.Events {
+		data, err := json.Marshal(event.EventData)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO events (match_id, round_number, event_type, tick, event_data)
+			VALUES ($1, $2, $3, $4, $5)`
+		s.logQuery(query, dbMatchID, event.RoundNumber, event.EventType)
+		if _, err := tx.ExecContext(ctx, query, dbMatchID, event.RoundNumber, event.EventType, event.Tick, data); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetMatch returns a match and its rounds/players by database ID.
+func (s *Store) GetMatch(ctx context.Context, id int64) (*ParsedData, error) {
+	parsed := &ParsedData{Players: []Player{}, Rounds: []Round{}, Events: []GameEvent{}}
+
+	query := `SELECT demo_id, map_name, duration, tick_rate FROM matches WHERE id = $1`
+	s.logQuery(query, id)
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&parsed.DemoID, &parsed.Metadata.MapName, &parsed.Metadata.Duration, &parsed.Metadata.TickRate); err != nil {
+		return nil, err
+	}
+
+	roundsQuery := `SELECT round_number, winner_side, win_reason, ct_score, t_score, duration_seconds, economy, player_stats
+		FROM rounds WHERE match_id = $1 ORDER BY round_number`
+	s.logQuery(roundsQuery, id)
+	rows, err := s.db.QueryContext(ctx, roundsQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var round Round
+		var economy, playerStats []byte
+		round.RoundData = map[string]interface{}{}
+		if err := rows.Scan(&round.RoundNumber, &round.WinnerSide, &round.WinReason,
+			&round.CTScore, &round.TScore, &round.DurationSeconds, &economy, &playerStats); err != nil {
+			return nil, err
+		}
+		if len(economy) > 0 {
+			if err := json.Unmarshal(economy, &round.Economy); err != nil {
+				return nil, err
+			}
+		}
+		if len(playerStats) > 0 {
+			if err := json.Unmarshal(playerStats, &round.PlayerStats); err != nil {
+				return nil, err
+			}
+		}
+		parsed.Rounds = append(parsed.Rounds, round)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	playersQuery := `SELECT p.steam_id, p.name, mp.team, mp.kills, mp.deaths, mp.assists, mp.adr, mp.hsp, mp.kast, mp.rating,
+		mp.damage, mp.headshot_kills, mp.flash_assists, mp.mvps, mp.clutches_1vn, mp.first_kills, mp.first_deaths, mp.utility_damage, mp.enemies_flashed
+		FROM match_players mp JOIN players p ON p.steam_id = mp.steam_id WHERE mp.match_id = $1`
+	s.logQuery(playersQuery, id)
+	playerRows, err := s.db.QueryContext(ctx, playersQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer playerRows.Close()
+
+	for playerRows.Next() {
+		player := Player{Stats: map[string]interface{}{}}
+		if err := playerRows.Scan(&player.SteamID, &player.Name, &player.Team, &player.Kills,
+			&player.Deaths, &player.Assists, &player.ADR, &player.HSP, &player.KAST, &player.Rating,
+			&player.Damage, &player.HeadshotKills, &player.FlashAssists, &player.MVPs, &player.Clutches1vN,
+			&player.FirstKills, &player.FirstDeaths, &player.UtilityDamage, &player.EnemiesFlashed); err != nil {
+			return nil, err
+		}
+		parsed.Players = append(parsed.Players, player)
+	}
+
+	return parsed, playerRows.Err()
+}
+
+// GetPlayerMatches returns the most recent matches a player appeared in,
+// newest first.
+func (s *Store) GetPlayerMatches(ctx context.Context, steamID string, limit int) ([]MatchSummary, error) {
+	query := `SELECT m.id, m.demo_id, m.match_id, m.map_number, m.map_name, m.created_at
+		FROM matches m JOIN match_players mp ON mp.match_id = m.id
+		WHERE mp.steam_id = $1 ORDER BY m.created_at DESC LIMIT $2`
+	s.logQuery(query, steamID, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, steamID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []MatchSummary
+	for rows.Next() {
+		var m MatchSummary
+		if err := rows.Scan(&m.ID, &m.DemoID, &m.MatchID, &m.MapNumber, &m.MapName, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// GetMatchRoundEvents returns the events for one round of a match,
+// optionally filtered to a single event type (e.g. "kill").
+func (s *Store) GetMatchRoundEvents(ctx context.Context, matchID int64, roundNumber int, eventType string) ([]GameEvent, error) {
+	query := `SELECT event_type, tick, round_number, event_data FROM events
+		WHERE match_id = $1 AND round_number = $2`
+	args := []interface{}{matchID, roundNumber}
+	if eventType != "" {
+		query += ` AND event_type = $3`
+		args = append(args, eventType)
+	}
+	query += ` ORDER BY tick`
+	s.logQuery(query, args...)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []GameEvent
+	for rows.Next() {
+		var event GameEvent
+		var data []byte
+		if err := rows.Scan(&event.EventType, &event.Tick, &event.RoundNumber, &data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &event.EventData); err != nil {
+			return nil, err
+		}
+		out = append(out, event)
+	}
+	return out, rows.Err()
+}
+
+// StartHousekeeping launches a goroutine that periodically prunes events
+// older than retention, so the events table doesn't grow unbounded.
+func (s *Store) StartHousekeeping(ctx context.Context, retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.pruneEvents(ctx, retention); err != nil {
+					log.Printf("Error pruning old events: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Store) pruneEvents(ctx context.Context, retention time.Duration) error {
+	query := `DELETE FROM events WHERE created_at < $1`
+	cutoff := time.Now().Add(-retention)
+	s.logQuery(query, cutoff)
+	_, err := s.db.ExecContext(ctx, query, cutoff)
+	return err
+}